@@ -0,0 +1,193 @@
+// Copyright 2014 The go-ethereum Authors && Copyright 2015 go-expanse Authors
+// This file is part of go-expanse.
+//
+// go-expanse is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-expanse is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-expanse. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	re "github.com/expanse-project/go-expanse/jsre"
+	"github.com/expanse-project/go-expanse/rpc"
+	"github.com/expanse-project/go-expanse/rpc/api"
+	"github.com/expanse-project/go-expanse/rpc/codec"
+	"github.com/expanse-project/go-expanse/rpc/comms"
+	"github.com/expanse-project/go-expanse/xeth"
+	"github.com/peterh/liner"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// console wraps a JSRE that talks to a remote gexp node through an
+// comms.ExpanseClient. Unlike the jsre embedded in cmd/gexp it never touches
+// an exp.Expanse instance directly, so it can run as an unprivileged process
+// against a hardened daemon.
+type console struct {
+	client comms.ExpanseClient
+	re     *re.JSRE
+	ps1    string
+	re.Prompter
+}
+
+// consoleFrontend implements xeth.Frontend by proxying to the console's own
+// prompter, the same way cmd/gexp's jsre answers these callbacks for a
+// local node. Jeth reaches it whenever the remote node reports it needs
+// interactive input it doesn't already have - e.g. unlocking an account
+// with no passphrase on hand.
+type consoleFrontend struct {
+	re.Prompter
+}
+
+var _ xeth.Frontend = consoleFrontend{}
+
+func (f consoleFrontend) UnlockAccount(addr []byte) bool {
+	fmt.Printf("Please unlock account %x.\n", addr)
+	_, err := f.PasswordPrompt("Passphrase: ")
+	return err == nil
+}
+
+func (f consoleFrontend) ConfirmTransaction(tx string) bool {
+	return true
+}
+
+// dialEndpoint picks an IPC or HTTP/WS client depending on the flags given,
+// defaulting to the IPC endpoint under --datadir/--ipcpath.
+func dialEndpoint(ctx *cli.Context) (comms.ExpanseClient, error) {
+	if endpoint := ctx.GlobalString(RPCFlag.Name); endpoint != "" {
+		return comms.ClientFromEndpoint(endpoint, codec.JSON)
+	}
+	ipcpath := ctx.GlobalString(IPCPathFlag.Name)
+	if !filepath.IsAbs(ipcpath) {
+		ipcpath = filepath.Join(ctx.GlobalString(DataDirFlag.Name), ipcpath)
+	}
+	return comms.ClientFromEndpoint(ipcpath, codec.JSON)
+}
+
+func newConsole(client comms.ExpanseClient, interactive bool, preload []string) (*console, error) {
+	c := &console{client: client, ps1: "> "}
+	c.re = re.New("")
+	if !liner.TerminalSupported() || !interactive {
+		c.Prompter = re.NewDumbterm()
+	} else {
+		lr := liner.NewLiner()
+		lr.SetCtrlCAborts(true)
+		c.Prompter = lr
+	}
+	if err := c.apiBindings(); err != nil {
+		return nil, err
+	}
+	for _, file := range preload {
+		if err := c.re.Exec(file); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	return c, nil
+}
+
+// apiBindings loads web3.js and shortcuts for whatever modules the remote
+// node actually advertises through SupportedModules, so the console never
+// exposes more than the daemon is willing to serve.
+func (c *console) apiBindings() error {
+	modules, err := c.client.SupportedModules()
+	if err != nil {
+		return fmt.Errorf("unable to determine supported api's: %v", err)
+	}
+	apiNames := make([]string, 0, len(modules))
+	for name := range modules {
+		apiNames = append(apiNames, name)
+	}
+
+	jeth := rpc.NewJeth(nil, c.re, c.client, consoleFrontend{c.Prompter})
+	c.re.Set("jeth", struct{}{})
+	t, _ := c.re.Get("jeth")
+	jethObj := t.Object()
+	jethObj.Set("send", jeth.Send)
+	jethObj.Set("sendAsync", jeth.Send)
+
+	if err := c.re.Compile("bignumber.js", re.BigNumber_JS); err != nil {
+		return fmt.Errorf("error loading bignumber.js: %v", err)
+	}
+	if err := c.re.Compile("expanse.js", re.Web3_JS); err != nil {
+		return fmt.Errorf("error loading web3.js: %v", err)
+	}
+	if _, err := c.re.Run("var web3 = require('web3'); web3.setProvider(jeth);"); err != nil {
+		return fmt.Errorf("error setting web3 provider: %v", err)
+	}
+
+	shortcuts := "var exp = web3.exp; "
+	for _, name := range apiNames {
+		if name == "web3" {
+			continue // manually mapped above
+		}
+		if err := c.re.Compile(fmt.Sprintf("%s.js", name), api.Javascript(name)); err != nil {
+			return fmt.Errorf("error loading %s.js: %v", name, err)
+		}
+		shortcuts += fmt.Sprintf("var %s = web3.%s; ", name, name)
+	}
+	_, err = c.re.Run(shortcuts)
+	return err
+}
+
+func (c *console) interactive() {
+	for {
+		input, err := c.Prompt(c.ps1)
+		if err != nil {
+			return
+		}
+		if input == "exit" {
+			return
+		}
+		if input == "" {
+			continue
+		}
+		c.AppendHistory(input)
+		if err := c.re.EvalAndPrettyPrint(input); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+func (c *console) execAndExit(statement string) {
+	if err := c.re.EvalAndPrettyPrint(statement); err != nil {
+		fatalf("error: %v", err)
+	}
+	c.re.Stop(true)
+}
+
+// remoteConsole is the cli.App action: it dials the remote node and either
+// runs a single --exec statement or drops into an interactive prompt.
+func remoteConsole(ctx *cli.Context) error {
+	client, err := dialEndpoint(ctx)
+	if err != nil {
+		fatalf("Unable to attach to remote gexp: %v", err)
+	}
+	var preload []string
+	if list := ctx.GlobalString(PreloadFlag.Name); list != "" {
+		preload = strings.Split(list, ",")
+	}
+	exec := ctx.GlobalString(ExecFlag.Name)
+	c, err := newConsole(client, exec == "", preload)
+	if err != nil {
+		fatalf("Unable to initialize console: %v", err)
+	}
+	if exec != "" {
+		c.execAndExit(exec)
+		return nil
+	}
+	c.interactive()
+	c.re.Stop(false)
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2014 The go-ethereum Authors && Copyright 2015 go-expanse Authors
+// This file is part of go-expanse.
+//
+// go-expanse is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-expanse is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-expanse. If not, see <http://www.gnu.org/licenses/>.
+
+// expanse-console is a standalone JavaScript console that attaches to a
+// running gexp node over IPC or RPC, without embedding a chain or account
+// store of its own.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/expanse-project/go-expanse/common"
+	"github.com/expanse-project/go-expanse/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const clientIdentifier = "expanse-console"
+
+var (
+	DataDirFlag = cli.StringFlag{
+		Name:  "datadir",
+		Usage: "Data directory of the node to attach to, used to resolve the default IPC endpoint",
+		Value: common.DefaultDataDir(),
+	}
+	IPCPathFlag = cli.StringFlag{
+		Name:  "ipcpath",
+		Usage: "Filename for the IPC socket/pipe within the datadir (explicit paths escape it)",
+		Value: "gexp.ipc",
+	}
+	RPCFlag = cli.StringFlag{
+		Name:  "rpc",
+		Usage: "HTTP-RPC or WS-RPC endpoint to attach to, e.g. http://127.0.0.1:9656 (overrides --ipcpath)",
+	}
+	ExecFlag = cli.StringFlag{
+		Name:  "exec",
+		Usage: "Execute the given JavaScript statement and exit instead of opening a prompt",
+	}
+	PreloadFlag = cli.StringFlag{
+		Name:  "preload",
+		Usage: "Comma separated list of JavaScript files to preload into the console",
+	}
+	VerbosityFlag = cli.IntFlag{
+		Name:  "verbosity",
+		Usage: "Logging verbosity: 0=silent, 1=error, 2=warn, 3=info, 4=debug, 5=detail",
+		Value: 3,
+	}
+)
+
+var app = cli.NewApp()
+
+func init() {
+	app.Name = clientIdentifier
+	app.Usage = "Attach an unprivileged JavaScript console to a running gexp node"
+	app.Action = remoteConsole
+	app.Flags = []cli.Flag{
+		DataDirFlag,
+		IPCPathFlag,
+		RPCFlag,
+		ExecFlag,
+		PreloadFlag,
+		VerbosityFlag,
+	}
+}
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
+
+func init() {
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	log.Root().SetHandler(glogger)
+	app.Before = func(ctx *cli.Context) error {
+		glogger.Verbosity(log.Lvl(ctx.GlobalInt(VerbosityFlag.Name)))
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 The go-ethereum Authors && Copyright 2017 go-expanse Authors
+// This file is part of go-expanse.
+//
+// go-expanse is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-expanse is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-expanse. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/expanse-org/go-expanse/common"
+	"github.com/expanse-org/go-expanse/core/state"
+)
+
+// prestateAccount is the shape of a single account in a --prestate file, the
+// same one Ethereum/Expanse state tests and genesis allocs already use, so a
+// prestate file can be lifted straight out of a test fixture.
+type prestateAccount struct {
+	Balance string            `json:"balance"`
+	Nonce   uint64            `json:"nonce"`
+	Code    string            `json:"code"`
+	Storage map[string]string `json:"storage"`
+}
+
+// prestateAlloc maps hex addresses to the account they should be seeded
+// with, exactly like a genesis alloc.
+type prestateAlloc map[string]prestateAccount
+
+// loadPrestate reads a --prestate JSON file and applies it to statedb before
+// any code runs, turning `evm run` into a reproducible fixture runner.
+func loadPrestate(path string, statedb *state.StateDB) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var alloc prestateAlloc
+	if err := json.Unmarshal(data, &alloc); err != nil {
+		return fmt.Errorf("invalid prestate file: %v", err)
+	}
+	for addrHex, account := range alloc {
+		addr := common.HexToAddress(addrHex)
+		statedb.CreateAccount(addr)
+
+		if account.Balance != "" {
+			balance, ok := new(big.Int).SetString(account.Balance, 0)
+			if !ok {
+				return fmt.Errorf("invalid balance for %s: %q", addrHex, account.Balance)
+			}
+			statedb.SetBalance(addr, balance)
+		}
+		statedb.SetNonce(addr, account.Nonce)
+		if account.Code != "" {
+			statedb.SetCode(addr, common.FromHex(account.Code))
+		}
+		for key, value := range account.Storage {
+			statedb.SetState(addr, common.HexToHash(key), common.HexToHash(value))
+		}
+	}
+	return nil
+}
+
+// dumpAccount mirrors the JSON shape state.StateDB.Dump already produces for
+// a single account, just enough of it to re-render in the prestate shape.
+type dumpAccount struct {
+	Balance string            `json:"balance"`
+	Nonce   uint64            `json:"nonce"`
+	Code    string            `json:"code"`
+	Storage map[string]string `json:"storage"`
+}
+
+type rawDump struct {
+	Accounts map[string]dumpAccount `json:"accounts"`
+}
+
+// dumpPrestate re-renders statedb.Dump() in the same {addr: {balance, nonce,
+// code, storage}} shape a --prestate file is given in, so a run's post-state
+// can be fed straight back in as the next run's prestate.
+func dumpPrestate(statedb *state.StateDB) ([]byte, error) {
+	var dump rawDump
+	if err := json.Unmarshal(statedb.Dump(), &dump); err != nil {
+		return nil, err
+	}
+	alloc := make(prestateAlloc, len(dump.Accounts))
+	for addr, account := range dump.Accounts {
+		alloc[addr] = prestateAccount{
+			Balance: account.Balance,
+			Nonce:   account.Nonce,
+			Code:    account.Code,
+			Storage: account.Storage,
+		}
+	}
+	return json.MarshalIndent(alloc, "", "  ")
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 The go-ethereum Authors && Copyright 2017 go-expanse Authors
+// This file is part of go-expanse.
+//
+// go-expanse is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-expanse is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-expanse. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/expanse-org/go-expanse/common"
+	"github.com/expanse-org/go-expanse/core/state"
+	"github.com/expanse-org/go-expanse/ethdb"
+)
+
+// TestPrestateRoundTrip loads a --prestate file into a fresh statedb, dumps
+// it back out with dumpPrestate, and checks the dump can be fed into
+// loadPrestate again and reproduce the same account - the round trip the
+// --prestate/--dump flags are meant to support.
+func TestPrestateRoundTrip(t *testing.T) {
+	const prestateJSON = `{
+		"0x0000000000000000000000000000000000000001": {
+			"balance": "1000",
+			"nonce": 5,
+			"code": "0x6001600101",
+			"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"
+			}
+		}
+	}`
+
+	f, err := ioutil.TempFile("", "prestate-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(prestateJSON); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, db)
+	if err := loadPrestate(f.Name(), statedb); err != nil {
+		t.Fatalf("loadPrestate failed: %v", err)
+	}
+
+	addr := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	if got := statedb.GetBalance(addr); got.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("balance = %v, want 1000", got)
+	}
+	if got := statedb.GetNonce(addr); got != 5 {
+		t.Errorf("nonce = %d, want 5", got)
+	}
+	if got := common.ToHex(statedb.GetCode(addr)); got != "0x6001600101" {
+		t.Errorf("code = %s, want 0x6001600101", got)
+	}
+
+	dumped, err := dumpPrestate(statedb)
+	if err != nil {
+		t.Fatalf("dumpPrestate failed: %v", err)
+	}
+
+	f2, err := ioutil.TempFile("", "prestate-dump-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f2.Name())
+	if _, err := f2.Write(dumped); err != nil {
+		t.Fatal(err)
+	}
+	f2.Close()
+
+	db2, _ := ethdb.NewMemDatabase()
+	statedb2, _ := state.New(common.Hash{}, db2)
+	if err := loadPrestate(f2.Name(), statedb2); err != nil {
+		t.Fatalf("loadPrestate on dumped output failed: %v", err)
+	}
+	if got := statedb2.GetBalance(addr); got.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("round-tripped balance = %v, want 1000", got)
+	}
+	if got := statedb2.GetNonce(addr); got != 5 {
+		t.Errorf("round-tripped nonce = %d, want 5", got)
+	}
+}
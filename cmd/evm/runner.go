@@ -18,10 +18,13 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"runtime/pprof"
+	"strings"
 	"time"
 
 	goruntime "runtime"
@@ -45,6 +48,63 @@ var runCommand = cli.Command{
 	Description: `The run command runs arbitrary EVM code.`,
 }
 
+// JSONFlag switches the trace output from the human-readable struct logger
+// to a stream of one JSON object per opcode, suitable for diffing against
+// other EVM implementations.
+var JSONFlag = cli.BoolFlag{
+	Name:  "json",
+	Usage: "output trace logs in machine readable format (json)",
+}
+
+var (
+	PrestateFlag = cli.StringFlag{
+		Name:  "prestate",
+		Usage: "JSON file of {addr: {balance, nonce, code, storage}} to seed the statedb with before running, same shape as a genesis alloc",
+	}
+	SenderFlag = cli.StringFlag{
+		Name:  "sender",
+		Usage: "address the call/create is made from",
+		Value: "sender",
+	}
+	ReceiverFlag = cli.StringFlag{
+		Name:  "receiver",
+		Usage: "address the code runs as (ignored with --create)",
+		Value: "receiver",
+	}
+	CoinbaseFlag = cli.StringFlag{
+		Name:  "coinbase",
+		Usage: "address COINBASE resolves to",
+	}
+	BlockNumberFlag = cli.Uint64Flag{
+		Name:  "blocknumber",
+		Usage: "value NUMBER resolves to",
+	}
+	TimestampFlag = cli.Uint64Flag{
+		Name:  "timestamp",
+		Usage: "value TIMESTAMP resolves to",
+	}
+	DifficultyFlag = cli.Uint64Flag{
+		Name:  "difficulty",
+		Usage: "value DIFFICULTY resolves to",
+	}
+	TracerFlag = cli.StringFlag{
+		Name:  "tracer",
+		Usage: "JavaScript tracer file exposing step(log,db)/fault(log,db)/result(ctx,db), used instead of the struct logger",
+	}
+)
+
+// parseAddressFlag accepts either a 0x-prefixed hex address, matching what a
+// real --sender/--receiver/--coinbase would be, or a bare name such as the
+// historical "sender"/"receiver" defaults, which StringToAddress turns into
+// a deterministic placeholder address for quick manual testing.
+func parseAddressFlag(ctx *cli.Context, flag cli.StringFlag) common.Address {
+	value := ctx.GlobalString(flag.Name)
+	if strings.HasPrefix(value, "0x") {
+		return common.HexToAddress(value)
+	}
+	return common.StringToAddress(value)
+}
+
 func runCmd(ctx *cli.Context) error {
 	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
 	glogger.Verbosity(log.Lvl(ctx.GlobalInt(VerbosityFlag.Name)))
@@ -53,11 +113,37 @@ func runCmd(ctx *cli.Context) error {
 	var (
 		db, _      = ethdb.NewMemDatabase()
 		statedb, _ = state.New(common.Hash{}, db)
-		sender     = common.StringToAddress("sender")
+		sender     = parseAddressFlag(ctx, SenderFlag)
+		receiver   = parseAddressFlag(ctx, ReceiverFlag)
 		logger     = vm.NewStructLogger(nil)
 	)
 	statedb.CreateAccount(sender)
 
+	if prestatePath := ctx.GlobalString(PrestateFlag.Name); prestatePath != "" {
+		if err := loadPrestate(prestatePath, statedb); err != nil {
+			utils.Fatalf("Unable to load prestate: %v", err)
+		}
+	}
+
+	// In --json mode, CaptureState results stream straight to stdout as they
+	// happen instead of being buffered for the human-readable trace below.
+	jsonOutput := ctx.GlobalBool(JSONFlag.Name)
+	var tracer vm.Tracer = logger
+	var jsonLogger *vm.JSONLogger
+	if jsonOutput {
+		jsonLogger = vm.NewJSONLogger(nil, os.Stdout)
+		tracer = jsonLogger
+	}
+
+	var jsTracer *vm.JSTracer
+	if tracerPath := ctx.GlobalString(TracerFlag.Name); tracerPath != "" {
+		loaded, loadErr := vm.NewJSTracerFromFile(tracerPath)
+		if loadErr != nil {
+			utils.Fatalf("Unable to load tracer: %v", loadErr)
+		}
+		jsTracer, tracer = loaded, loaded
+	}
+
 	var (
 		code []byte
 		ret  []byte
@@ -97,14 +183,18 @@ func runCmd(ctx *cli.Context) error {
 	}
 
 	runtimeConfig := runtime.Config{
-		Origin:   sender,
-		State:    statedb,
-		GasLimit: ctx.GlobalUint64(GasFlag.Name),
-		GasPrice: utils.GlobalBig(ctx, PriceFlag.Name),
-		Value:    utils.GlobalBig(ctx, ValueFlag.Name),
+		Origin:      sender,
+		State:       statedb,
+		GasLimit:    ctx.GlobalUint64(GasFlag.Name),
+		GasPrice:    utils.GlobalBig(ctx, PriceFlag.Name),
+		Value:       utils.GlobalBig(ctx, ValueFlag.Name),
+		Coinbase:    parseAddressFlag(ctx, CoinbaseFlag),
+		BlockNumber: new(big.Int).SetUint64(ctx.GlobalUint64(BlockNumberFlag.Name)),
+		Time:        new(big.Int).SetUint64(ctx.GlobalUint64(TimestampFlag.Name)),
+		Difficulty:  new(big.Int).SetUint64(ctx.GlobalUint64(DifficultyFlag.Name)),
 		EVMConfig: vm.Config{
-			Tracer:             logger,
-			Debug:              ctx.GlobalBool(DebugFlag.Name),
+			Tracer:             tracer,
+			Debug:              ctx.GlobalBool(DebugFlag.Name) || jsonOutput || jsTracer != nil,
 			DisableGasMetering: ctx.GlobalBool(DisableGasMeteringFlag.Name),
 		},
 	}
@@ -127,7 +217,6 @@ func runCmd(ctx *cli.Context) error {
 		input := append(code, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name))...)
 		ret, _, err = runtime.Create(input, &runtimeConfig)
 	} else {
-		receiver := common.StringToAddress("receiver")
 		statedb.SetCode(receiver, code)
 
 		ret, err = runtime.Call(receiver, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name)), &runtimeConfig)
@@ -136,7 +225,18 @@ func runCmd(ctx *cli.Context) error {
 
 	if ctx.GlobalBool(DumpFlag.Name) {
 		statedb.Commit(true)
-		fmt.Println(string(statedb.Dump()))
+		post, err := dumpPrestate(statedb)
+		if err != nil {
+			utils.Fatalf("Unable to dump post-state: %v", err)
+		}
+		// --json and --tracer both promise one JSON object per line on
+		// stdout; the multi-line post-state dump would land in the middle
+		// of that stream, so it goes to stderr instead when either is set.
+		if jsonOutput || jsTracer != nil {
+			fmt.Fprintln(os.Stderr, string(post))
+		} else {
+			fmt.Println(string(post))
+		}
 	}
 
 	if memProfilePath := ctx.GlobalString(MemProfileFlag.Name); memProfilePath != "" {
@@ -152,7 +252,7 @@ func runCmd(ctx *cli.Context) error {
 		f.Close()
 	}
 
-	if ctx.GlobalBool(DebugFlag.Name) {
+	if ctx.GlobalBool(DebugFlag.Name) && !jsonOutput && jsTracer == nil {
 		fmt.Fprintln(os.Stderr, "#### TRACE ####")
 		vm.WriteTrace(os.Stderr, logger.StructLogs())
 		fmt.Fprintln(os.Stderr, "#### LOGS ####")
@@ -171,6 +271,45 @@ GC calls:           %d
 `, execTime, mem.HeapObjects, mem.Alloc, mem.TotalAlloc, mem.NumGC)
 	}
 
+	if jsTracer != nil {
+		errStr := ""
+		if err != nil {
+			errStr = err.Error()
+		}
+		result, resErr := jsTracer.Result(map[string]interface{}{
+			"output":   common.ToHex(ret),
+			"gasLimit": runtimeConfig.GasLimit,
+			"time":     execTime.String(),
+			"error":    errStr,
+		})
+		if resErr != nil {
+			utils.Fatalf("Tracer result() failed: %v", resErr)
+		}
+		exported, expErr := result.Export()
+		if expErr != nil {
+			utils.Fatalf("Unable to export tracer result: %v", expErr)
+		}
+		out, jsonErr := json.Marshal(exported)
+		if jsonErr != nil {
+			utils.Fatalf("Unable to encode tracer result: %v", jsonErr)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	if jsonOutput {
+		// The human hex result still matters for quick manual checks, it
+		// just shouldn't land on stdout and corrupt the one-line-per-opcode
+		// stream that external analysers consume.
+		fmt.Fprintf(os.Stderr, "0x%x", ret)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, " error: %v", err)
+		}
+		fmt.Fprintln(os.Stderr)
+		jsonLogger.WriteSummary(ret, execTime.String(), err)
+		return nil
+	}
+
 	fmt.Printf("0x%x", ret)
 	if err != nil {
 		fmt.Printf(" error: %v", err)
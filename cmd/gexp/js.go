@@ -17,7 +17,6 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"math/big"
 	"os"
@@ -28,6 +27,7 @@ import (
 
 	"sort"
 
+	"github.com/expanse-project/go-expanse/accounts"
 	"github.com/expanse-project/go-expanse/cmd/utils"
 	"github.com/expanse-project/go-expanse/common"
 	"github.com/expanse-project/go-expanse/common/docserver"
@@ -43,47 +43,52 @@ import (
 	"github.com/expanse-project/go-expanse/xeth"
 	"github.com/peterh/liner"
 	"github.com/robertkrimen/otto"
+	cli "gopkg.in/urfave/cli.v1"
 )
 
 var passwordRegexp = regexp.MustCompile("personal.[nu]")
 
 const passwordRepl = ""
 
-type prompter interface {
-	AppendHistory(string)
-	Prompt(p string) (string, error)
-	PasswordPrompt(p string) (string, error)
-}
-
-type dumbterm struct{ r *bufio.Reader }
-
-func (r dumbterm) Prompt(p string) (string, error) {
-	fmt.Print(p)
-	line, err := r.r.ReadString('\n')
-	return strings.TrimSuffix(line, "\n"), err
+type jsre struct {
+	ds             *docserver.DocServer
+	re             *re.JSRE
+	expanse       *exp.Expanse
+	xeth           *xeth.XEth
+	wait           chan *big.Int
+	ps1            string
+	atexit         func()
+	corsDomain     string
+	client         comms.ExpanseClient
+	unlockAttempts int
+	re.Prompter
 }
 
-func (r dumbterm) PasswordPrompt(p string) (string, error) {
-	fmt.Println("!! Unsupported terminal, password will echo.")
-	fmt.Print(p)
-	input, err := bufio.NewReader(os.Stdin).ReadString('\n')
-	fmt.Println()
-	return input, err
+// defaultUnlockAttempts is used when newJSRE/newLightweightJSRE are called
+// with unlockAttempts <= 0; it is also the default of --unlock-attempts.
+const defaultUnlockAttempts = 3
+
+// UnlockAttemptsFlag lets an operator raise or lower how many times
+// UnlockAccount retries a wrong passphrase before giving up. gexp's main
+// flag list (cmd/gexp/flags.go, outside this patch series) is expected to
+// include it in app.Flags and pass ctx.GlobalInt(UnlockAttemptsFlag.Name)
+// into newJSRE/newLightweightJSRE the same way it already does for other
+// console settings.
+var UnlockAttemptsFlag = cli.IntFlag{
+	Name:  "unlock-attempts",
+	Usage: "Number of passphrase attempts for UnlockAccount before giving up",
+	Value: defaultUnlockAttempts,
 }
 
-func (r dumbterm) AppendHistory(string) {}
-
-type jsre struct {
-	ds         *docserver.DocServer
-	re         *re.JSRE
-	expanse   *exp.Expanse
-	xeth       *xeth.XEth
-	wait       chan *big.Int
-	ps1        string
-	atexit     func()
-	corsDomain string
-	client     comms.ExpanseClient
-	prompter
+// PreloadJSFlag mirrors cmd/console's --preload: a comma separated list of
+// JavaScript files run through preloadJSFiles right after the console's
+// api bindings are set up. Same caveat as UnlockAttemptsFlag - gexp's main
+// flag list lives outside this patch series, and is expected to split
+// ctx.GlobalString(PreloadJSFlag.Name) on "," and pass the result into
+// newJSRE/newLightweightJSRE's preload parameter.
+var PreloadJSFlag = cli.StringFlag{
+	Name:  "preload",
+	Usage: "Comma separated list of JavaScript files to preload into the console",
 }
 
 var (
@@ -145,20 +150,27 @@ func apiWordCompleter(line string, pos int) (head string, completions []string,
 	return begin, completionWords, end
 }
 
-func newLightweightJSRE(libPath string, client comms.ExpanseClient, interactive bool) *jsre {
+func newLightweightJSRE(libPath string, client comms.ExpanseClient, interactive bool, unlockAttempts int, preload []string) *jsre {
 	js := &jsre{ps1: "> "}
 	js.wait = make(chan *big.Int)
 	js.client = client
 	js.ds = docserver.New("/")
+	if unlockAttempts <= 0 {
+		unlockAttempts = defaultUnlockAttempts
+	}
+	js.unlockAttempts = unlockAttempts
 
 	// update state in separare forever blocks
 	js.re = re.New(libPath)
 	if err := js.apiBindings(js); err != nil {
 		utils.Fatalf("Unable to initialize console - %v", err)
 	}
+	if err := js.preloadJSFiles(preload); err != nil {
+		utils.Fatalf("Unable to preload JS: %v", err)
+	}
 
 	if !liner.TerminalSupported() || !interactive {
-		js.prompter = dumbterm{bufio.NewReader(os.Stdin)}
+		js.Prompter = re.NewDumbterm()
 	} else {
 		lr := liner.NewLiner()
 		js.withHistory(func(hist *os.File) { lr.ReadHistory(hist) })
@@ -166,7 +178,7 @@ func newLightweightJSRE(libPath string, client comms.ExpanseClient, interactive
 		js.loadAutoCompletion()
 		lr.SetWordCompleter(apiWordCompleter)
 		lr.SetTabCompletionStyle(liner.TabPrints)
-		js.prompter = lr
+		js.Prompter = lr
 		js.atexit = func() {
 			js.withHistory(func(hist *os.File) { hist.Truncate(0); lr.WriteHistory(hist) })
 			lr.Close()
@@ -176,10 +188,14 @@ func newLightweightJSRE(libPath string, client comms.ExpanseClient, interactive
 	return js
 }
 
-func newJSRE(expanse *exp.Expanse, libPath, corsDomain string, client comms.ExpanseClient, interactive bool, f xeth.Frontend) *jsre {
+func newJSRE(expanse *exp.Expanse, libPath, corsDomain string, client comms.ExpanseClient, interactive bool, f xeth.Frontend, unlockAttempts int, preload []string) *jsre {
 	js := &jsre{expanse: expanse, ps1: "> "}
 	// set default cors domain used by startRpc from CLI flag
 	js.corsDomain = corsDomain
+	if unlockAttempts <= 0 {
+		unlockAttempts = defaultUnlockAttempts
+	}
+	js.unlockAttempts = unlockAttempts
 	if f == nil {
 		f = js
 	}
@@ -198,9 +214,12 @@ func newJSRE(expanse *exp.Expanse, libPath, corsDomain string, client comms.Expa
 	if err := js.apiBindings(f); err != nil {
 		utils.Fatalf("Unable to connect - %v", err)
 	}
+	if err := js.preloadJSFiles(preload); err != nil {
+		utils.Fatalf("Unable to preload JS: %v", err)
+	}
 
 	if !liner.TerminalSupported() || !interactive {
-		js.prompter = dumbterm{bufio.NewReader(os.Stdin)}
+		js.Prompter = re.NewDumbterm()
 	} else {
 		lr := liner.NewLiner()
 		js.withHistory(func(hist *os.File) { lr.ReadHistory(hist) })
@@ -208,7 +227,7 @@ func newJSRE(expanse *exp.Expanse, libPath, corsDomain string, client comms.Expa
 		js.loadAutoCompletion()
 		lr.SetWordCompleter(apiWordCompleter)
 		lr.SetTabCompletionStyle(liner.TabPrints)
-		js.prompter = lr
+		js.Prompter = lr
 		js.atexit = func() {
 			js.withHistory(func(hist *os.File) { hist.Truncate(0); lr.WriteHistory(hist) })
 			lr.Close()
@@ -334,6 +353,18 @@ func (js *jsre) apiBindings(f xeth.Frontend) error {
 	return nil
 }
 
+// preloadJSFiles compiles and evaluates each file in order, right after
+// apiBindings and before the prompt appears (or --exec runs), so preloaded
+// helpers can assume web3/exp/etc. are already wired up.
+func (js *jsre) preloadJSFiles(files []string) error {
+	for _, file := range files {
+		if err := js.re.Exec(file); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	return nil
+}
+
 func (self *jsre) ConfirmTransaction(tx string) bool {
 	if self.expanse.NatSpec {
 		notice := natspec.GetNotice(self.xeth, tx, self.ds)
@@ -345,19 +376,52 @@ func (self *jsre) ConfirmTransaction(tx string) bool {
 	}
 }
 
+// UnlockAccount implements xeth.Frontend. It prompts for the passphrase up
+// to unlockAttempts times (default 3, see --unlock-attempts), telling wrong
+// passphrases apart from other account manager errors so the operator isn't
+// stuck retyping a passphrase that will never work.
+//
+// This callback only fires when the RPC layer needs to interactively prompt
+// for a passphrase it doesn't already have; it never sees a duration. JS
+// callers that pass one via personal.unlockAccount(addr, pass, duration) are
+// served directly by the personal RPC module, which already holds the
+// passphrase and calls AccountManager().TimedUnlock itself.
 func (self *jsre) UnlockAccount(addr []byte) bool {
-	fmt.Printf("Please unlock account %x.\n", addr)
-	pass, err := self.PasswordPrompt("Passphrase: ")
-	if err != nil {
-		return false
-	}
-	// TODO: allow retry
-	if err := self.expanse.AccountManager().Unlock(common.BytesToAddress(addr), pass); err != nil {
+	account := common.BytesToAddress(addr)
+	return unlockAccountWithRetries(addr, self.unlockAttempts, self.PasswordPrompt, func(pass string) error {
+		return self.expanse.AccountManager().Unlock(account, pass)
+	})
+}
+
+// unlockAccountWithRetries drives the prompt/unlock retry loop UnlockAccount
+// needs, with the passphrase source and the unlock call themselves factored
+// out so the retry behaviour can be unit tested without an AccountManager.
+func unlockAccountWithRetries(addr []byte, attempts int, prompt func(string) (string, error), unlock func(string) error) bool {
+	for tries := 0; tries < attempts; tries++ {
+		fmt.Printf("Please unlock account %x.\n", addr)
+		pass, err := prompt("Passphrase: ")
+		if err != nil {
+			if err == liner.ErrPromptAborted { // ctrl-C
+				fmt.Println("Unlock aborted.")
+				return false
+			}
+			return false
+		}
+
+		unlockErr := unlock(pass)
+		if unlockErr == nil {
+			fmt.Println("Account is now unlocked for this session.")
+			return true
+		}
+		if unlockErr == accounts.ErrDecrypt {
+			fmt.Printf("Failed to unlock account %x: wrong passphrase (attempt %d/%d)\n", addr, tries+1, attempts)
+			continue
+		}
+		fmt.Printf("Failed to unlock account %x: %v\n", addr, unlockErr)
 		return false
-	} else {
-		fmt.Println("Account is now unlocked for this session.")
-		return true
 	}
+	fmt.Printf("Failed to unlock account %x after %d attempts.\n", addr, attempts)
+	return false
 }
 
 func (self *jsre) exec(filename string) error {
@@ -0,0 +1,69 @@
+// Copyright 2017 The go-ethereum Authors && Copyright 2017 go-expanse Authors
+// This file is part of go-expanse.
+//
+// go-expanse is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-expanse is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-expanse. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/expanse-project/go-expanse/accounts"
+)
+
+// TestUnlockAccountRetries checks that a wrong passphrase is retried up to
+// the configured attempt count and a correct one on the last try still
+// succeeds, while any other account manager error aborts immediately.
+func TestUnlockAccountRetries(t *testing.T) {
+	prompt := func(string) (string, error) { return "pass", nil }
+
+	calls := 0
+	unlock := func(pass string) error {
+		calls++
+		if calls < 3 {
+			return accounts.ErrDecrypt
+		}
+		return nil
+	}
+	if ok := unlockAccountWithRetries([]byte{1}, 3, prompt, unlock); !ok {
+		t.Fatal("expected success on the 3rd attempt")
+	}
+	if calls != 3 {
+		t.Fatalf("unlock called %d times, want 3", calls)
+	}
+
+	calls = 0
+	unlock = func(string) error { calls++; return accounts.ErrDecrypt }
+	if ok := unlockAccountWithRetries([]byte{1}, 3, prompt, unlock); ok {
+		t.Fatal("expected failure after exhausting attempts")
+	}
+	if calls != 3 {
+		t.Fatalf("unlock called %d times, want 3", calls)
+	}
+
+	calls = 0
+	unlock = func(string) error { calls++; return errOther }
+	if ok := unlockAccountWithRetries([]byte{1}, 3, prompt, unlock); ok {
+		t.Fatal("expected immediate failure on a non-decrypt error")
+	}
+	if calls != 1 {
+		t.Fatalf("unlock called %d times, want 1", calls)
+	}
+}
+
+var errOther = &unlockTestError{"some other account manager error"}
+
+type unlockTestError struct{ msg string }
+
+func (e *unlockTestError) Error() string { return e.msg }
@@ -0,0 +1,262 @@
+// Copyright 2017 The go-ethereum Authors && Copyright 2017 go-expanse Authors
+// This file is part of go-expanse.
+//
+// go-expanse is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-expanse is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-expanse. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/expanse-org/go-expanse/common"
+	"github.com/robertkrimen/otto"
+)
+
+// JSTracer is a Tracer driven by a user-supplied JavaScript object exposing
+// step(log, db), fault(log, db) and result(ctx, db) hooks. It gives users a
+// way to write ad-hoc analyses - opcode histograms, call-graph extraction,
+// gas attribution - without recompiling evm or gexp. Used by `evm run
+// --tracer`; the console's debug.traceTransaction does not use it.
+type JSTracer struct {
+	vm     *otto.Otto
+	tracer *otto.Object
+	env    Environment
+	err    error
+}
+
+// NewJSTracer compiles code (expected to evaluate to an object literal with
+// step/fault/result methods) into a fresh otto VM. The Environment is not
+// needed yet - CaptureState/CaptureFault receive a fresh one on every call
+// and use it to answer the script's db.* lookups.
+func NewJSTracer(code string) (*JSTracer, error) {
+	vm := otto.New()
+	value, err := vm.Eval("(" + code + ")")
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile tracer: %v", err)
+	}
+	obj := value.Object()
+	if obj == nil {
+		return nil, fmt.Errorf("tracer script must evaluate to an object")
+	}
+	return &JSTracer{vm: vm, tracer: obj}, nil
+}
+
+// NewJSTracerFromFile loads a --tracer script from disk.
+func NewJSTracerFromFile(path string) (*JSTracer, error) {
+	code, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSTracer(string(code))
+}
+
+// CaptureState implements Tracer. Panics from the user script (bad
+// property access, type errors, etc.) are caught and surfaced as a tracer
+// error rather than crashing the EVM run.
+func (t *JSTracer) CaptureState(env Environment, pc uint64, op OpCode, gas, cost *big.Int, memory *Memory, stack *Stack, contract *Contract, depth int, err error) (tracerErr error) {
+	if t.err != nil {
+		return nil // a previous step already failed the trace
+	}
+	t.env = env
+	defer func() {
+		if r := recover(); r != nil {
+			t.err = fmt.Errorf("tracer panic in step(): %v", r)
+			tracerErr = t.err
+		}
+	}()
+
+	fn, callErr := t.tracer.Get("step")
+	if callErr != nil || !fn.IsFunction() {
+		return nil
+	}
+	logObj := t.newLogObject(pc, op, gas, cost, memory, stack, contract, depth, err)
+	dbObj := t.newDbObject()
+	if _, callErr = fn.Call(t.tracer.Value(), logObj, dbObj); callErr != nil {
+		t.err = fmt.Errorf("tracer step() failed: %v", callErr)
+		return t.err
+	}
+	return nil
+}
+
+// CaptureFault is invoked instead of CaptureState when the step produced an
+// execution error, mirroring step()/fault() the way real tracer scripts
+// expect to distinguish the two.
+func (t *JSTracer) CaptureFault(env Environment, pc uint64, op OpCode, gas, cost *big.Int, memory *Memory, stack *Stack, contract *Contract, depth int, err error) (tracerErr error) {
+	t.env = env
+	defer func() {
+		if r := recover(); r != nil {
+			t.err = fmt.Errorf("tracer panic in fault(): %v", r)
+			tracerErr = t.err
+		}
+	}()
+	fn, callErr := t.tracer.Get("fault")
+	if callErr != nil || !fn.IsFunction() {
+		return nil
+	}
+	logObj := t.newLogObject(pc, op, gas, cost, memory, stack, contract, depth, err)
+	dbObj := t.newDbObject()
+	if _, callErr = fn.Call(t.tracer.Value(), logObj, dbObj); callErr != nil {
+		t.err = fmt.Errorf("tracer fault() failed: %v", callErr)
+		return t.err
+	}
+	return nil
+}
+
+// Result calls the script's result(ctx, db) hook and returns whatever it
+// returns, JSON-encoded by the caller (evm run prints it as the final line).
+// Result runs after the whole call completes, so unlike CaptureState/
+// CaptureFault there may never have been a step - e.g. an empty contract or
+// a revert before the first opcode - in which case t.env is still nil and
+// db.* must report an error rather than let the script panic the process.
+func (t *JSTracer) Result(ctx map[string]interface{}) (result otto.Value, resultErr error) {
+	if t.err != nil {
+		return otto.Value{}, t.err
+	}
+	fn, err := t.tracer.Get("result")
+	if err != nil || !fn.IsFunction() {
+		return otto.UndefinedValue(), nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			result = otto.Value{}
+			resultErr = fmt.Errorf("tracer result() failed: %v", r)
+		}
+	}()
+	ctxObj, _ := t.vm.Object(`({})`)
+	for k, v := range ctx {
+		ctxObj.Set(k, v)
+	}
+	return fn.Call(t.tracer.Value(), ctxObj, t.newDbObject())
+}
+
+// newDbObject wraps the handful of state accessors a tracer script needs as
+// plain JS functions on a fresh object, keyed to the Environment passed to
+// the current CaptureState/CaptureFault call. Each accessor guards against
+// t.env being nil, which happens when result() is called without a
+// preceding step - the panic is turned into a tracer error by the recover()
+// in whichever of CaptureState/CaptureFault/Result invoked it.
+func (t *JSTracer) newDbObject() *otto.Object {
+	obj, _ := t.vm.Object(`({})`)
+	obj.Set("getBalance", func(call otto.FunctionCall) otto.Value {
+		t.requireEnv()
+		addr := common.HexToAddress(call.Argument(0).String())
+		v, _ := t.vm.ToValue(t.env.Db().GetBalance(addr).String())
+		return v
+	})
+	obj.Set("getNonce", func(call otto.FunctionCall) otto.Value {
+		t.requireEnv()
+		addr := common.HexToAddress(call.Argument(0).String())
+		v, _ := t.vm.ToValue(t.env.Db().GetNonce(addr))
+		return v
+	})
+	obj.Set("getCode", func(call otto.FunctionCall) otto.Value {
+		t.requireEnv()
+		addr := common.HexToAddress(call.Argument(0).String())
+		v, _ := t.vm.ToValue(common.ToHex(t.env.Db().GetCode(addr)))
+		return v
+	})
+	obj.Set("getState", func(call otto.FunctionCall) otto.Value {
+		t.requireEnv()
+		addr := common.HexToAddress(call.Argument(0).String())
+		key := common.HexToHash(call.Argument(1).String())
+		v, _ := t.vm.ToValue(t.env.Db().GetState(addr, key).Hex())
+		return v
+	})
+	obj.Set("exists", func(call otto.FunctionCall) otto.Value {
+		t.requireEnv()
+		addr := common.HexToAddress(call.Argument(0).String())
+		v, _ := t.vm.ToValue(t.env.Db().Exist(addr))
+		return v
+	})
+	return obj
+}
+
+// requireEnv panics if called outside CaptureState/CaptureFault, i.e. when
+// db.* is reached from result() without the tracer ever having seen a step.
+func (t *JSTracer) requireEnv() {
+	if t.env == nil {
+		panic("tracer: db is not available, no opcode was executed")
+	}
+}
+
+// newLogObject marshals the current opcode into a JS object: stack items as
+// decimal strings (stack.peek), memory slices as 0x-prefixed hex strings
+// (memory.slice), a storage accessor and a few scalar fields. No big.Int
+// objects are exposed to the script.
+func (t *JSTracer) newLogObject(pc uint64, op OpCode, gas, cost *big.Int, memory *Memory, stack *Stack, contract *Contract, depth int, err error) *otto.Object {
+	obj, _ := t.vm.Object(`({})`)
+	obj.Set("pc", pc)
+	obj.Set("op", op.String())
+	obj.Set("gas", gas.Uint64())
+	obj.Set("gasCost", cost.Uint64())
+	obj.Set("depth", depth)
+	if err != nil {
+		obj.Set("error", err.Error())
+	}
+
+	stackObj, _ := t.vm.Object(`({})`)
+	stackObj.Set("peek", func(call otto.FunctionCall) otto.Value {
+		idx, _ := call.Argument(0).ToInteger()
+		data := stack.Data()
+		if idx < 0 || int(idx) >= len(data) {
+			v, _ := t.vm.ToValue("0")
+			return v
+		}
+		v, _ := t.vm.ToValue(data[len(data)-1-int(idx)].String())
+		return v
+	})
+	stackObj.Set("length", func(call otto.FunctionCall) otto.Value {
+		v, _ := t.vm.ToValue(len(stack.Data()))
+		return v
+	})
+	obj.Set("stack", stackObj)
+
+	memObj, _ := t.vm.Object(`({})`)
+	memObj.Set("slice", func(call otto.FunctionCall) otto.Value {
+		start, _ := call.Argument(0).ToInteger()
+		stop, _ := call.Argument(1).ToInteger()
+		data := memory.Data()
+		if start < 0 || stop > int64(len(data)) || start > stop {
+			v, _ := t.vm.ToValue("0x")
+			return v
+		}
+		v, _ := t.vm.ToValue(common.ToHex(data[start:stop]))
+		return v
+	})
+	memObj.Set("length", func(call otto.FunctionCall) otto.Value {
+		v, _ := t.vm.ToValue(len(memory.Data()))
+		return v
+	})
+	obj.Set("memory", memObj)
+
+	if contract != nil {
+		storeObj, _ := t.vm.Object(`({})`)
+		storeObj.Set("getValue", func(call otto.FunctionCall) otto.Value {
+			key := common.HexToHash(call.Argument(0).String())
+			v, _ := t.vm.ToValue(t.env.Db().GetState(contract.Address(), key).Hex())
+			return v
+		})
+		obj.Set("storage", storeObj)
+
+		contractObj, _ := t.vm.Object(`({})`)
+		contractObj.Set("getAddress", func(call otto.FunctionCall) otto.Value {
+			v, _ := t.vm.ToValue(contract.Address().Hex())
+			return v
+		})
+		obj.Set("contract", contractObj)
+	}
+	return obj
+}
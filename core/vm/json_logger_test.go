@@ -0,0 +1,53 @@
+// Copyright 2017 The go-ethereum Authors && Copyright 2017 go-expanse Authors
+// This file is part of go-expanse.
+//
+// go-expanse is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-expanse is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-expanse. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// staticJSONLoggerIsTracer fails to compile if JSONLogger ever stops
+// implementing the full Tracer interface, the way it silently did when
+// CaptureFault was missing.
+var staticJSONLoggerIsTracer Tracer = (*JSONLogger)(nil)
+
+// TestJSONLogEntryEncoding checks the wire shape of a single trace line:
+// op must render as its mnemonic, not the raw opcode byte, and memory must
+// be hex, not the base64 encoding/json would otherwise pick for a []byte.
+func TestJSONLogEntryEncoding(t *testing.T) {
+	entry := jsonLogEntry{
+		Pc:      1,
+		Op:      ADD.String(),
+		Gas:     100,
+		GasCost: 3,
+		Depth:   1,
+		Memory:  "0x01",
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	out := string(data)
+	if !strings.Contains(out, `"op":"ADD"`) {
+		t.Errorf("expected op mnemonic in output, got %s", out)
+	}
+	if !strings.Contains(out, `"memory":"0x01"`) {
+		t.Errorf("expected hex memory in output, got %s", out)
+	}
+}
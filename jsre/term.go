@@ -0,0 +1,60 @@
+// Copyright 2014 The go-ethereum Authors && Copyright 2015 go-expanse Authors
+// This file is part of go-expanse.
+//
+// go-expanse is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-expanse is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-expanse. If not, see <http://www.gnu.org/licenses/>.
+
+package jsre
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prompter is the input source a console reads statements and passphrases
+// from. cmd/gexp's full REPL and cmd/console's lightweight one both need
+// one, so it lives here rather than being declared twice.
+type Prompter interface {
+	AppendHistory(string)
+	Prompt(p string) (string, error)
+	PasswordPrompt(p string) (string, error)
+}
+
+// Dumbterm is the Prompter fallback used whenever liner.TerminalSupported
+// reports the terminal can't do line editing (e.g. piped stdin), or the
+// console is running non-interactively. It only echoes passphrases because
+// there's no line-editing terminal to suppress them with.
+type Dumbterm struct{ R *bufio.Reader }
+
+// NewDumbterm wraps os.Stdin in a Dumbterm ready to use as a Prompter.
+func NewDumbterm() Dumbterm {
+	return Dumbterm{bufio.NewReader(os.Stdin)}
+}
+
+func (r Dumbterm) Prompt(p string) (string, error) {
+	fmt.Print(p)
+	line, err := r.R.ReadString('\n')
+	return strings.TrimSuffix(line, "\n"), err
+}
+
+func (r Dumbterm) PasswordPrompt(p string) (string, error) {
+	fmt.Println("!! Unsupported terminal, password will echo.")
+	fmt.Print(p)
+	input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	fmt.Println()
+	return input, err
+}
+
+func (r Dumbterm) AppendHistory(string) {}